@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/smorand/email-extractor/src/pkg/emlx"
+)
+
+const (
+	indexFilename   = "index.json"
+	threadsFilename = "threads.json"
+)
+
+// IndexEntry describes one extracted message in index.json.
+type IndexEntry struct {
+	MessageID   string   `json:"message_id"`
+	Subject     string   `json:"subject"`
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	Date        string   `json:"date"`
+	InReplyTo   string   `json:"in_reply_to,omitempty"`
+	References  string   `json:"references,omitempty"`
+	Path        string   `json:"path"`
+	Attachments []string `json:"attachments"`
+}
+
+// Thread is one conversation tree in threads.json: the Message-IDs of every
+// message the union-find pass grouped together, in index.json order.
+type Thread struct {
+	MessageIDs []string `json:"message_ids"`
+}
+
+// rawMessage is one unparsed message pulled out of an mbox file or a
+// directory of .eml files, paired with a name used to build its output
+// folder.
+type rawMessage struct {
+	name    string
+	content string
+}
+
+// extractMailbox extracts every message found at inputPath - an mbox file or
+// a directory of .eml files - into its own numbered folder under
+// outputRoot, then writes index.json and threads.json describing the set.
+// It is the implementation behind the CLI's --mbox flag.
+func extractMailbox(inputPath, outputRoot string, maxAttachmentSize int64, formats []string) error {
+	inputPath = expandPath(inputPath)
+	outputRoot = expandPath(outputRoot)
+
+	messages, err := loadMessages(inputPath)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("no messages found at %s", inputPath)
+	}
+
+	if err := os.MkdirAll(outputRoot, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var index []IndexEntry
+	for i, m := range messages {
+		folder := fmt.Sprintf("%04d_%s", i+1, sanitizeFilename(m.name))
+		outputDir := filepath.Join(outputRoot, folder)
+
+		email, msg, err := emlx.ParseHeader(strings.NewReader(m.content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   Warning: failed to parse message %d (%s): %v\n", i+1, m.name, err)
+			continue
+		}
+
+		result, err := extractParsed(email, msg, outputDir, maxAttachmentSize, formats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   Warning: failed to extract message %d (%s): %v\n", i+1, m.name, err)
+			continue
+		}
+
+		rel, err := filepath.Rel(outputRoot, result.PrimaryFile)
+		if err != nil {
+			rel = result.PrimaryFile
+		}
+		var attPaths []string
+		for _, att := range result.Attachments {
+			attPaths = append(attPaths, att.Path)
+		}
+		index = append(index, IndexEntry{
+			MessageID:   email.MessageID,
+			Subject:     email.Subject,
+			From:        email.From,
+			To:          email.To,
+			Date:        email.Date,
+			InReplyTo:   email.InReplyTo,
+			References:  email.References,
+			Path:        rel,
+			Attachments: attPaths,
+		})
+		fmt.Fprintf(os.Stderr, "✅ [%d/%d] %s\n", i+1, len(messages), email.Subject)
+	}
+
+	if err := writeJSON(filepath.Join(outputRoot, indexFilename), index); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(outputRoot, threadsFilename), buildThreads(index)); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "\n📬 Extracted %d of %d messages to %s\n", len(index), len(messages), outputRoot)
+	return nil
+}
+
+// loadMessages reads inputPath as either a directory of .eml files or a
+// single mbox file, and returns each message's raw RFC 5322 text along with
+// a name to derive its output folder from.
+func loadMessages(inputPath string) ([]rawMessage, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("input not found: %s", inputPath)
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory: %w", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".eml") {
+				continue
+			}
+			names = append(names, entry.Name())
+		}
+		sort.Strings(names)
+
+		var messages []rawMessage
+		for _, name := range names {
+			content, err := os.ReadFile(filepath.Join(inputPath, name))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "   Warning: failed to read %s: %v\n", name, err)
+				continue
+			}
+			messages = append(messages, rawMessage{
+				name:    strings.TrimSuffix(name, filepath.Ext(name)),
+				content: string(content),
+			})
+		}
+		return messages, nil
+	}
+
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mbox file: %w", err)
+	}
+	defer f.Close()
+
+	chunks, err := splitMbox(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mbox file: %w", err)
+	}
+
+	messages := make([]rawMessage, len(chunks))
+	for i, chunk := range chunks {
+		messages[i] = rawMessage{name: fmt.Sprintf("message_%d", i+1), content: chunk}
+	}
+	return messages, nil
+}
+
+// splitMbox splits an mbox file into the raw text of each message. Per the
+// mboxo/mboxrd convention, a message starts at a line beginning with
+// "From " that is either the first line of the file or follows a blank
+// line; that separator line itself is dropped. Message body lines that were
+// escaped because they looked like a separator ("From " at mboxrd quotes
+// them as ">From ") are unescaped.
+func splitMbox(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var messages []string
+	var cur strings.Builder
+	prevBlank := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") && prevBlank {
+			if cur.Len() > 0 {
+				messages = append(messages, cur.String())
+				cur.Reset()
+			}
+			prevBlank = false
+			continue
+		}
+		if strings.HasPrefix(line, ">From ") {
+			line = line[1:]
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		prevBlank = line == ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if cur.Len() > 0 {
+		messages = append(messages, cur.String())
+	}
+	return messages, nil
+}
+
+// buildThreads groups index into conversation trees by union-find over
+// Message-ID, In-Reply-To and References edges: any two messages connected
+// by one of those headers end up in the same thread.
+func buildThreads(index []IndexEntry) []Thread {
+	parent := map[string]string{}
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[ra] = rb
+		}
+	}
+
+	ensure := func(id string) {
+		if _, ok := parent[id]; !ok {
+			parent[id] = id
+		}
+	}
+
+	for _, entry := range index {
+		if entry.MessageID == "" {
+			continue
+		}
+		ensure(entry.MessageID)
+		for _, ref := range references(entry) {
+			ensure(ref)
+			union(entry.MessageID, ref)
+		}
+	}
+
+	groups := map[string][]string{}
+	for _, entry := range index {
+		if entry.MessageID == "" {
+			continue
+		}
+		root := find(entry.MessageID)
+		groups[root] = append(groups[root], entry.MessageID)
+	}
+
+	var roots []string
+	for root := range groups {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	threads := make([]Thread, 0, len(roots))
+	for _, root := range roots {
+		threads = append(threads, Thread{MessageIDs: groups[root]})
+	}
+	return threads
+}
+
+// references returns every Message-ID entry links to via In-Reply-To or
+// References, which is itself a whitespace-separated list of Message-IDs.
+func references(entry IndexEntry) []string {
+	var ids []string
+	if entry.InReplyTo != "" {
+		ids = append(ids, strings.Fields(entry.InReplyTo)...)
+	}
+	if entry.References != "" {
+		ids = append(ids, strings.Fields(entry.References)...)
+	}
+	return ids
+}
+
+// writeJSON writes v as indented JSON, without HTML-escaping characters like
+// '<' and '>' that are common and harmless in Message-ID values.
+func writeJSON(path string, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
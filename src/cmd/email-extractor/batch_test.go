@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMbox(t *testing.T) {
+	tests := []struct {
+		name string
+		mbox string
+		want []string
+	}{
+		{
+			name: "single message",
+			mbox: "From sender@example.com Mon Jan  1 00:00:00 2024\nSubject: hi\n\nbody\n",
+			want: []string{"Subject: hi\n\nbody\n"},
+		},
+		{
+			name: "two messages separated by a blank line",
+			mbox: "From a@example.com Mon Jan  1 00:00:00 2024\n" +
+				"Subject: one\n\nfirst body\n" +
+				"\nFrom b@example.com Mon Jan  1 00:00:00 2024\n" +
+				"Subject: two\n\nsecond body\n",
+			want: []string{
+				"Subject: one\n\nfirst body\n\n",
+				"Subject: two\n\nsecond body\n",
+			},
+		},
+		{
+			name: "escaped From line in the body is unescaped",
+			mbox: "From a@example.com Mon Jan  1 00:00:00 2024\n" +
+				"Subject: hi\n\n>From the start, this looked odd.\nbody\n",
+			want: []string{"Subject: hi\n\nFrom the start, this looked odd.\nbody\n"},
+		},
+		{
+			name: "a From in the middle of a line is left alone",
+			mbox: "From a@example.com Mon Jan  1 00:00:00 2024\n" +
+				"Subject: hi\n\nquoting someone: From now on I quit.\n",
+			want: []string{"Subject: hi\n\nquoting someone: From now on I quit.\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitMbox(strings.NewReader(tt.mbox))
+			if err != nil {
+				t.Fatalf("splitMbox returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitMbox() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildThreads(t *testing.T) {
+	t.Run("messages linked by In-Reply-To share a thread", func(t *testing.T) {
+		index := []IndexEntry{
+			{MessageID: "<1@a>"},
+			{MessageID: "<2@a>", InReplyTo: "<1@a>"},
+			{MessageID: "<3@a>", References: "<1@a> <2@a>"},
+		}
+		threads := buildThreads(index)
+		if len(threads) != 1 {
+			t.Fatalf("got %d threads, want 1: %#v", len(threads), threads)
+		}
+		got := append([]string(nil), threads[0].MessageIDs...)
+		want := []string{"<1@a>", "<2@a>", "<3@a>"}
+		if !sameSet(got, want) {
+			t.Errorf("thread = %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("unrelated messages form separate threads", func(t *testing.T) {
+		index := []IndexEntry{
+			{MessageID: "<1@a>"},
+			{MessageID: "<2@b>"},
+		}
+		threads := buildThreads(index)
+		if len(threads) != 2 {
+			t.Fatalf("got %d threads, want 2: %#v", len(threads), threads)
+		}
+	})
+
+	t.Run("messages without a Message-ID are ignored", func(t *testing.T) {
+		index := []IndexEntry{
+			{MessageID: ""},
+			{MessageID: "<1@a>"},
+		}
+		threads := buildThreads(index)
+		if len(threads) != 1 || len(threads[0].MessageIDs) != 1 {
+			t.Fatalf("got %#v, want a single thread containing only <1@a>", threads)
+		}
+	})
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, v := range a {
+		seen[v] = true
+	}
+	for _, v := range b {
+		if !seen[v] {
+			return false
+		}
+	}
+	return true
+}
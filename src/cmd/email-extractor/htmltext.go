@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlToText renders HTML email bodies down to readable markdown. It walks
+// the tokenizer's token stream rather than using regexes, so multi-line
+// <script>/<style> blocks, '>' inside attribute values, and nested tags are
+// handled the way a browser would rather than by accident. Block structure
+// that the old regex version dropped - blockquotes, links, tables, nested
+// lists, preformatted code - is preserved as the closest markdown
+// equivalent.
+func htmlToText(htmlContent string) string {
+	r := newHTMLRenderer()
+	z := html.NewTokenizer(strings.NewReader(htmlContent))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return cleanupWhitespace(r.out.String())
+		case html.TextToken:
+			r.text(string(z.Text()))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			var attrs map[string]string
+			if hasAttr {
+				attrs = map[string]string{}
+				for {
+					key, val, more := z.TagAttr()
+					attrs[string(key)] = string(val)
+					if !more {
+						break
+					}
+				}
+			}
+			r.startTag(tag, attrs)
+			if tt == html.SelfClosingTagToken {
+				r.endTag(tag)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			r.endTag(string(name))
+		}
+	}
+}
+
+// htmlRenderer walks an HTML token stream and accumulates markdown. It
+// tracks just enough nesting state - list frames, table cells, link text,
+// blockquote depth - to render block structure instead of collapsing
+// everything to a wall of text.
+type htmlRenderer struct {
+	out strings.Builder
+
+	skipTag string // non-empty while inside <script> or <style>
+	inPre   bool
+
+	quoteDepth int
+
+	lists []listFrame
+
+	linkHref []string
+	linkText []*strings.Builder
+
+	tables []*tableFrame
+
+	// sinks is the stack of buffers currently "live", in nesting order - a
+	// table cell, a link's text, another table cell nested inside that
+	// link, and so on. write always goes to the top of this stack, which is
+	// what keeps e.g. a link inside a table cell from writing past the
+	// link's buffer straight into the cell.
+	sinks []*strings.Builder
+}
+
+type listFrame struct {
+	ordered bool
+	n       int
+}
+
+type tableFrame struct {
+	rows   [][]string
+	row    []string
+	cell   strings.Builder
+	inCell bool
+
+	// nested is true for a <table> found while another one is still open.
+	// Its rows are flattened into the enclosing cell as plain text instead
+	// of a second pipe table, which GFM has no syntax for nesting inside a
+	// single cell.
+	nested bool
+}
+
+func newHTMLRenderer() *htmlRenderer {
+	return &htmlRenderer{}
+}
+
+// write sends s to whichever buffer is currently "live": the innermost open
+// table cell or link text, or the main output if neither is open.
+func (r *htmlRenderer) write(s string) {
+	if n := len(r.sinks); n > 0 {
+		r.sinks[n-1].WriteString(s)
+		return
+	}
+	if r.quoteDepth > 0 && !r.inPre {
+		s = strings.ReplaceAll(s, "\n", "\n"+strings.Repeat("> ", r.quoteDepth))
+	}
+	r.out.WriteString(s)
+}
+
+func (r *htmlRenderer) text(s string) {
+	if r.skipTag != "" {
+		return
+	}
+	if r.inPre {
+		r.write(s)
+		return
+	}
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		return
+	}
+	if isSpace(rune(s[0])) {
+		collapsed = " " + collapsed
+	}
+	if isSpace(rune(s[len(s)-1])) {
+		collapsed += " "
+	}
+	r.write(collapsed)
+}
+
+func isSpace(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (r *htmlRenderer) startTag(tag string, attrs map[string]string) {
+	switch tag {
+	case "script", "style":
+		r.skipTag = tag
+	case "br":
+		r.write("\n")
+	case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6":
+		r.write("\n\n")
+	case "blockquote":
+		r.quoteDepth++
+		r.write("\n")
+	case "pre":
+		r.inPre = true
+		r.write("\n```\n")
+	case "code":
+		if !r.inPre {
+			r.write("`")
+		}
+	case "ul":
+		r.lists = append(r.lists, listFrame{ordered: false})
+	case "ol":
+		r.lists = append(r.lists, listFrame{ordered: true})
+	case "li":
+		r.write("\n" + r.listPrefix())
+	case "a":
+		buf := &strings.Builder{}
+		r.linkHref = append(r.linkHref, attrs["href"])
+		r.linkText = append(r.linkText, buf)
+		r.sinks = append(r.sinks, buf)
+	case "img":
+		if src := attrs["src"]; src != "" {
+			r.write(fmt.Sprintf("\n![](%s)\n", src))
+		}
+	case "table":
+		r.tables = append(r.tables, &tableFrame{nested: len(r.tables) > 0})
+	case "tr":
+		if n := len(r.tables); n > 0 {
+			r.tables[n-1].row = nil
+		}
+	case "td", "th":
+		if n := len(r.tables); n > 0 {
+			t := r.tables[n-1]
+			t.cell.Reset()
+			t.inCell = true
+			r.sinks = append(r.sinks, &t.cell)
+		}
+	}
+}
+
+func (r *htmlRenderer) endTag(tag string) {
+	switch tag {
+	case "script", "style":
+		if r.skipTag == tag {
+			r.skipTag = ""
+		}
+	case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6":
+		r.write("\n\n")
+	case "blockquote":
+		if r.quoteDepth > 0 {
+			r.quoteDepth--
+		}
+		r.write("\n")
+	case "pre":
+		r.inPre = false
+		r.write("\n```\n")
+	case "code":
+		if !r.inPre {
+			r.write("`")
+		}
+	case "ul", "ol":
+		if len(r.lists) > 0 {
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+		r.write("\n")
+	case "a":
+		n := len(r.linkHref)
+		if n == 0 {
+			return
+		}
+		href := r.linkHref[n-1]
+		text := strings.TrimSpace(r.linkText[n-1].String())
+		r.linkHref = r.linkHref[:n-1]
+		r.linkText = r.linkText[:n-1]
+		r.popSink()
+		switch {
+		case href == "":
+			r.write(text)
+		case text == "":
+			r.write(fmt.Sprintf("[%s](%s)", href, href))
+		default:
+			r.write(fmt.Sprintf("[%s](%s)", text, href))
+		}
+	case "td", "th":
+		if n := len(r.tables); n > 0 {
+			t := r.tables[n-1]
+			if t.inCell {
+				r.popSink()
+			}
+			// Collapse embedded newlines (from <br>, nested <p>s, a
+			// flattened nested table, ...) to spaces: a GFM table row has to
+			// stay on a single line.
+			t.row = append(t.row, strings.Join(strings.Fields(t.cell.String()), " "))
+			t.cell.Reset()
+			t.inCell = false
+		}
+	case "tr":
+		if n := len(r.tables); n > 0 {
+			t := r.tables[n-1]
+			if len(t.row) > 0 {
+				t.rows = append(t.rows, t.row)
+			}
+			t.row = nil
+		}
+	case "table":
+		n := len(r.tables)
+		if n == 0 {
+			return
+		}
+		t := r.tables[n-1]
+		r.tables = r.tables[:n-1]
+		if t.nested {
+			r.write(flattenTable(t.rows))
+		} else {
+			r.write("\n" + renderGFMTable(t.rows) + "\n")
+		}
+	}
+}
+
+// popSink removes the innermost live buffer, e.g. when a <td> or <a> that
+// pushed one closes.
+func (r *htmlRenderer) popSink() {
+	if n := len(r.sinks); n > 0 {
+		r.sinks = r.sinks[:n-1]
+	}
+}
+
+// listPrefix returns the bullet or number for the next <li>, indented for
+// nesting and, for an <ol>, incrementing that level's counter.
+func (r *htmlRenderer) listPrefix() string {
+	if len(r.lists) == 0 {
+		return "- "
+	}
+	indent := strings.Repeat("  ", len(r.lists)-1)
+	top := &r.lists[len(r.lists)-1]
+	if top.ordered {
+		top.n++
+		return fmt.Sprintf("%s%d. ", indent, top.n)
+	}
+	return indent + "- "
+}
+
+// renderGFMTable turns parsed table rows into a GitHub-flavored markdown
+// pipe table, treating the first row as the header. Each cell's "|" is
+// escaped, since an unescaped one would be read as another column
+// separator and desync every column after it.
+func renderGFMTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	formatRow := func(row []string) string {
+		cells := make([]string, cols)
+		for i := range cells {
+			if i < len(row) {
+				cells[i] = strings.ReplaceAll(row[i], "|", "\\|")
+			}
+		}
+		return "| " + strings.Join(cells, " | ") + " |\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(formatRow(rows[0]))
+	sep := make([]string, cols)
+	for i := range sep {
+		sep[i] = "---"
+	}
+	sb.WriteString("| " + strings.Join(sep, " | ") + " |\n")
+	for _, row := range rows[1:] {
+		sb.WriteString(formatRow(row))
+	}
+	return sb.String()
+}
+
+// flattenTable renders a <table> found nested inside another table's cell
+// as plain inline text rather than a second pipe table: GFM cells can't
+// contain another table, and concatenating the nested table's own pipe
+// syntax into the enclosing cell just produces unparseable garbage.
+func flattenTable(rows [][]string) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, " | "))
+	}
+	return strings.Join(lines, "; ")
+}
+
+// cleanupWhitespace collapses runs of blank lines left over from block-level
+// tags and trims trailing whitespace, the same tidy-up the old regex-based
+// renderer did.
+func cleanupWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	var cleaned []string
+	prevBlank := false
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		isBlank := strings.TrimSpace(trimmed) == ""
+		if !(isBlank && prevBlank) {
+			cleaned = append(cleaned, trimmed)
+		}
+		prevBlank = isBlank
+	}
+	return strings.TrimSpace(strings.Join(cleaned, "\n"))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHtmlToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "plain paragraph",
+			html: "<p>Hello world</p>",
+			want: "Hello world",
+		},
+		{
+			name: "link with text",
+			html: `<p>See <a href="http://example.com">the site</a> for more.</p>`,
+			want: "See [the site](http://example.com) for more.",
+		},
+		{
+			name: "link with no text falls back to the href",
+			html: `<a href="http://example.com"></a>`,
+			want: "[http://example.com](http://example.com)",
+		},
+		{
+			name: "link with no href keeps just the text",
+			html: `<a>bare text</a>`,
+			want: "bare text",
+		},
+		{
+			name: "blockquote is prefixed on every line",
+			html: "<blockquote>line one<br>line two</blockquote>",
+			want: "> line one\n> line two",
+		},
+		{
+			name: "unordered list",
+			html: "<ul><li>one</li><li>two</li></ul>",
+			want: "- one\n- two",
+		},
+		{
+			name: "ordered list numbers increment",
+			html: "<ol><li>first</li><li>second</li></ol>",
+			want: "1. first\n2. second",
+		},
+		{
+			name: "pre preserves whitespace",
+			html: "<pre>a   b\nc</pre>",
+			want: "```\na   b\nc\n```",
+		},
+		{
+			name: "table renders as a GFM pipe table",
+			html: "<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>",
+			want: "| A | B |\n| --- | --- |\n| 1 | 2 |",
+		},
+		{
+			name: "link inside a table cell stays in the link's own buffer",
+			html: `<table><tr><td><a href="http://example.com">Click here</a></td><td>B</td></tr></table>`,
+			want: "| [Click here](http://example.com) | B |\n| --- | --- |",
+		},
+		{
+			name: "a pipe in cell content is escaped instead of desyncing columns",
+			html: `<table><tr><th>Item</th><th>Price</th></tr><tr><td>Widget</td><td>$10 | was $20</td></tr></table>`,
+			want: "| Item | Price |\n| --- | --- |\n| Widget | $10 \\| was $20 |",
+		},
+		{
+			name: "a table nested in a cell is flattened instead of splicing a second pipe table",
+			html: `<table><tr><td>Price: <table><tr><td>inner cell</td></tr></table> more outer text</td><td>Buy now</td></tr></table>`,
+			want: "| Price: inner cell more outer text | Buy now |\n| --- | --- |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.TrimSpace(htmlToText(tt.html))
+			if got != tt.want {
+				t.Errorf("htmlToText(%q) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,600 @@
+// Command email-extractor extracts the content and attachments of a .eml
+// file to markdown. It is a thin wrapper around the emlx library: all MIME
+// parsing lives there, this binary only handles CLI flags, file I/O and
+// markdown rendering.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/smorand/email-extractor/src/pkg/emlx"
+)
+
+const attachmentsDir = "attachments"
+
+// defaultFormats is used when the user doesn't pass --format, preserving
+// the tool's original markdown-only behavior.
+var defaultFormats = []string{"md"}
+
+// Attachment is a file that has been saved to disk alongside the email
+// markdown, as opposed to emlx.Attachment, which only holds an in-memory
+// reader.
+type Attachment struct {
+	Filename  string
+	Path      string
+	Size      int64
+	Truncated bool
+
+	// Rendered marks a Path that points at a nested message's own rendered
+	// output file rather than raw attachment bytes (used for message/rfc822
+	// attachments). Renderers that inline attachment content, like
+	// jsonRenderer, must treat this as a link instead of something to read
+	// and embed.
+	Rendered bool
+}
+
+// ExtractionResult contains everything written to disk for one email. It is
+// also the input to every Renderer, so it carries both the parsed email and
+// the bookkeeping (CidPaths, OutputDir) a renderer needs to resolve
+// attachment and embedded-file references.
+type ExtractionResult struct {
+	Attachments []Attachment
+	EmailName   string
+	Markdown    string
+	CidPaths    map[string]string
+
+	// OutputFiles lists every rendered output file, in the order given to
+	// --format. PrimaryFile is the first of them, used for anything that
+	// needs a single canonical file (the index in batch mode, the link a
+	// nested message's parent markdown points at).
+	OutputFiles []string
+	PrimaryFile string
+
+	Email     *emlx.Email
+	OutputDir string
+}
+
+func main() {
+	var (
+		cleanup           = flag.Bool("cleanup", false, "Clean up extraction directory after reading")
+		maxAttachmentSize = flag.Int64("max-attachment-size", 0, "Maximum attachment size in bytes to keep; larger attachments are truncated (0 = unlimited)")
+		mbox              = flag.Bool("mbox", false, "Treat the input as an mbox file or a directory of .eml files, and extract every message under output_directory")
+		format            = flag.String("format", "md", "Comma-separated output formats to generate: md, json, html, org")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <eml_file> [output_directory]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Extract content and attachments from .eml files to markdown format.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  eml_file           Path to the .eml file to extract\n")
+		fmt.Fprintf(os.Stderr, "                     With --mbox: an mbox file or a directory of .eml files\n")
+		fmt.Fprintf(os.Stderr, "  output_directory   Optional: Base directory for extraction\n")
+		fmt.Fprintf(os.Stderr, "                     Default: Same directory as .eml file\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s message.eml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s ~/Downloads/email.eml ~/Documents/extracted\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --cleanup message.eml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --mbox ~/Mail/archive.mbox ~/Documents/archive\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --format=md,json,html message.eml\n\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	formats, err := parseFormats(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	inputPath := args[0]
+	var outputDir string
+	if len(args) >= 2 {
+		outputDir = args[1]
+	}
+
+	if *mbox {
+		if outputDir == "" {
+			outputDir = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "_extracted"
+		}
+		if err := extractMailbox(inputPath, outputDir, *maxAttachmentSize, formats); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error extracting mailbox: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	result, err := extractEmailContent(inputPath, outputDir, *maxAttachmentSize, formats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error extracting email: %v\n", err)
+		os.Exit(1)
+	}
+
+	printExtractionSummary(result)
+
+	if *cleanup {
+		cleanupExtraction(result.OutputDir)
+	} else {
+		fmt.Fprintf(os.Stderr, "\n💡 Tip: Use --cleanup flag to automatically remove extraction directory after reading\n")
+		fmt.Fprintf(os.Stderr, "   Or manually clean up: rm -rf \"%s\"\n", result.OutputDir)
+	}
+}
+
+// parseFormats splits a comma-separated --format value into a deduplicated
+// list of formats, validating each against the known Renderer set.
+func parseFormats(format string) ([]string, error) {
+	var formats []string
+	seen := map[string]bool{}
+	for _, f := range strings.Split(format, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" || seen[f] {
+			continue
+		}
+		if rendererFor(f) == nil {
+			return nil, fmt.Errorf("unknown output format %q (want md, json, html or org)", f)
+		}
+		seen[f] = true
+		formats = append(formats, f)
+	}
+	if len(formats) == 0 {
+		return defaultFormats, nil
+	}
+	return formats, nil
+}
+
+func extractEmailContent(emlPath, outputDir string, maxAttachmentSize int64, formats []string) (*ExtractionResult, error) {
+	emlPath = expandPath(emlPath)
+	if _, err := os.Stat(emlPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("email file not found: %s", emlPath)
+	}
+
+	emlDir := filepath.Dir(emlPath)
+	emlFilename := strings.TrimSuffix(filepath.Base(emlPath), ".eml")
+
+	fmt.Fprintf(os.Stderr, "📧 Extracting: %s\n", emlPath)
+
+	f, err := os.Open(emlPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open email file: %w", err)
+	}
+	defer f.Close()
+
+	// Parse the headers first so the Subject is known before we have to
+	// decide where attachments get streamed to.
+	email, msg, err := emlx.ParseHeader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var folderName string
+	if email.Subject != "" {
+		folderName = sanitizeFilename(email.Subject)
+		if len(folderName) > 100 {
+			folderName = folderName[:100]
+		}
+	} else {
+		folderName = emlFilename
+	}
+
+	if !strings.HasSuffix(folderName, "_email") {
+		folderName += "_email"
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Join(emlDir, folderName)
+	} else {
+		outputDir = expandPath(outputDir)
+		if !strings.HasSuffix(outputDir, folderName) {
+			outputDir = filepath.Join(outputDir, folderName)
+		}
+	}
+
+	result, err := extractParsed(email, msg, outputDir, maxAttachmentSize, formats)
+	if err != nil {
+		return nil, err
+	}
+	result.EmailName = folderName
+
+	return result, nil
+}
+
+// extractParsed creates outputDir (already chosen by the caller as where
+// this message's folder lives), streams msg's body into it and finishes the
+// extraction. It is shared by the single-file CLI flow and the mbox/batch
+// flow in batch.go, which differ only in how they pick outputDir.
+func extractParsed(email *emlx.Email, msg *mail.Message, outputDir string, maxAttachmentSize int64, formats []string) (*ExtractionResult, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "📁 Output to: %s\n", outputDir)
+	fmt.Fprintf(os.Stderr, "\n📎 Extracting attachments...\n")
+
+	attDir := filepath.Join(outputDir, attachmentsDir)
+	var attachments []Attachment
+	opts := emlx.Options{
+		MaxAttachmentSize: maxAttachmentSize,
+		AttachmentSink:    streamingSink(attDir, &attachments),
+	}
+
+	if err := emlx.ParseBody(msg, email, opts); err != nil {
+		return nil, fmt.Errorf("failed to extract email body: %w", err)
+	}
+
+	for i, att := range attachments {
+		if i < len(email.Attachments) {
+			att.Size = email.Attachments[i].Size
+			att.Truncated = email.Attachments[i].Truncated
+			attachments[i] = att
+		}
+		suffix := ""
+		if att.Truncated {
+			suffix = " [TRUNCATED]"
+		}
+		fmt.Fprintf(os.Stderr, "   Extracted attachment: %s (%d bytes)%s\n", att.Filename, att.Size, suffix)
+	}
+
+	return finishEmail(email, outputDir, attachments, formats)
+}
+
+// streamingSink returns an emlx.AttachmentSink that streams each
+// attachment's decoded content straight to a file under attDir, so a
+// multi-GB attachment never has to be buffered in memory. The saved
+// records are appended to *saved in the same order attachments are
+// parsed, matching the order of the resulting Email.Attachments.
+func streamingSink(attDir string, saved *[]Attachment) emlx.AttachmentSink {
+	return func(meta emlx.Attachment) (io.WriteCloser, error) {
+		if err := os.MkdirAll(attDir, 0755); err != nil {
+			return nil, err
+		}
+
+		filename := sanitizeFilename(meta.Filename)
+		fullPath := makeUniqueFilepath(filepath.Join(attDir, filename))
+		filename = filepath.Base(fullPath)
+
+		out, err := os.Create(fullPath)
+		if err != nil {
+			return nil, err
+		}
+
+		*saved = append(*saved, Attachment{
+			Filename: filename,
+			Path:     filepath.Join(attachmentsDir, filename),
+		})
+		return out, nil
+	}
+}
+
+// writeEmail writes one parsed email (attachments, embedded files, nested
+// messages and the rendered output) under outputDir. It is used for
+// message/rfc822 attachments, which get their own attachments/<name>_email/
+// folder with their own rendered output; unlike the top-level email, their
+// attachments are buffered in memory rather than streamed, since
+// Options.AttachmentSink is tied to a single destination directory known
+// only once at the top level.
+func writeEmail(email *emlx.Email, outputDir string, formats []string) (*ExtractionResult, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	attDir := filepath.Join(outputDir, attachmentsDir)
+
+	attachments, err := saveAttachments(attDir, email.Attachments)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Error saving attachments: %v\n", err)
+	}
+
+	return finishEmail(email, outputDir, attachments, formats)
+}
+
+// finishEmail saves embedded files and nested messages, then renders
+// attachments has already been saved to disk by the caller, either
+// streamed (top level) or buffered (nested emails), into every format in
+// formats. Each format is written as outputDir/email.<ext>.
+func finishEmail(email *emlx.Email, outputDir string, attachments []Attachment, formats []string) (*ExtractionResult, error) {
+	attDir := filepath.Join(outputDir, attachmentsDir)
+
+	for _, warning := range email.Warnings {
+		fmt.Fprintf(os.Stderr, "   Warning: %s\n", warning)
+	}
+
+	cidPaths := map[string]string{}
+	for _, embedded := range email.EmbeddedFiles {
+		saved, err := saveAttachment(attDir, embedded.Filename, embedded.Data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   Warning: Failed to save embedded file %s: %v\n", embedded.Filename, err)
+			continue
+		}
+		if embedded.ContentID != "" {
+			cidPaths[embedded.ContentID] = saved.Path
+		}
+	}
+
+	for _, nested := range email.NestedEmails {
+		folder := nestedFolderName(nested.Filename)
+		nestedResult, err := writeEmail(nested.Email, filepath.Join(attDir, folder), formats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   Warning: Failed to write nested message %s: %v\n", nested.Filename, err)
+			continue
+		}
+
+		var size int64
+		if info, err := os.Stat(nestedResult.PrimaryFile); err == nil {
+			size = info.Size()
+		}
+
+		attachments = append(attachments, Attachment{
+			Filename: nested.Filename,
+			Path:     filepath.Join(attachmentsDir, folder, filepath.Base(nestedResult.PrimaryFile)),
+			Size:     size,
+			Rendered: true,
+		})
+	}
+
+	result := &ExtractionResult{
+		Email:       email,
+		Attachments: attachments,
+		OutputDir:   outputDir,
+		CidPaths:    cidPaths,
+		Markdown:    createEmailMarkdown(email, attachments, cidPaths),
+	}
+
+	for _, format := range formats {
+		renderer := rendererFor(format)
+		path := filepath.Join(outputDir, "email"+renderer.Extension())
+		if err := renderToFile(renderer, result, path); err != nil {
+			return nil, fmt.Errorf("failed to write %s output: %w", format, err)
+		}
+		result.OutputFiles = append(result.OutputFiles, path)
+	}
+	result.PrimaryFile = result.OutputFiles[0]
+
+	return result, nil
+}
+
+// renderToFile runs renderer over result and writes the result to path.
+func renderToFile(renderer Renderer, result *ExtractionResult, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return renderer.Render(f, result)
+}
+
+// nestedFolderName derives the attachments/<name>_email/ folder name for a
+// message/rfc822 attachment from its filename.
+func nestedFolderName(filename string) string {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	name = sanitizeFilename(name)
+	if !strings.HasSuffix(name, "_email") {
+		name += "_email"
+	}
+	return name
+}
+
+// saveAttachments writes each attachment's content to attDir, returning the
+// on-disk Attachment records in the same order. A single failed attachment
+// is logged and skipped rather than aborting the whole email.
+func saveAttachments(attDir string, attachments []emlx.Attachment) ([]Attachment, error) {
+	var saved []Attachment
+	for _, att := range attachments {
+		if att.Filename == "" {
+			continue
+		}
+		result, err := saveAttachment(attDir, att.Filename, att.Data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   Warning: Failed to save attachment %s: %v\n", att.Filename, err)
+			continue
+		}
+		saved = append(saved, result)
+		fmt.Fprintf(os.Stderr, "   Extracted attachment: %s (%d bytes)\n", result.Filename, result.Size)
+	}
+	return saved, nil
+}
+
+func saveAttachment(attDir, filename string, data io.Reader) (Attachment, error) {
+	if err := os.MkdirAll(attDir, 0755); err != nil {
+		return Attachment{}, err
+	}
+
+	filename = sanitizeFilename(filename)
+	fullPath := makeUniqueFilepath(filepath.Join(attDir, filename))
+	filename = filepath.Base(fullPath)
+
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer out.Close()
+
+	size, err := io.Copy(out, data)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	return Attachment{
+		Filename: filename,
+		Path:     filepath.Join(attachmentsDir, filename),
+		Size:     size,
+	}, nil
+}
+
+func createEmailMarkdown(email *emlx.Email, attachments []Attachment, cidPaths map[string]string) string {
+	var md strings.Builder
+
+	md.WriteString(fmt.Sprintf("# Email: %s\n\n", email.Subject))
+	md.WriteString("## Metadata\n\n")
+	md.WriteString(fmt.Sprintf("- **From:** %s\n", email.From))
+
+	if len(email.To) > 0 {
+		md.WriteString(fmt.Sprintf("- **To:** %s\n", strings.Join(email.To, ", ")))
+	}
+
+	if len(email.Cc) > 0 {
+		md.WriteString(fmt.Sprintf("- **Cc:** %s\n", strings.Join(email.Cc, ", ")))
+	}
+
+	md.WriteString(fmt.Sprintf("- **Date:** %s\n", email.Date))
+	md.WriteString(fmt.Sprintf("- **Subject:** %s\n", email.Subject))
+	if email.Protocol != "" {
+		md.WriteString(fmt.Sprintf("- **Protocol:** %s\n", email.Protocol))
+	}
+	md.WriteString("\n")
+
+	if len(attachments) > 0 {
+		md.WriteString("## Attachments\n\n")
+		for _, att := range attachments {
+			size := formatFileSize(att.Size)
+			if att.Truncated {
+				md.WriteString(fmt.Sprintf("- **%s** (%s, truncated) - `%s`\n", att.Filename, size, att.Path))
+			} else {
+				md.WriteString(fmt.Sprintf("- **%s** (%s) - `%s`\n", att.Filename, size, att.Path))
+			}
+		}
+		md.WriteString("\n")
+	}
+
+	md.WriteString("---\n\n")
+	md.WriteString("## Message\n\n")
+	md.WriteString(renderBody(email, cidPaths))
+	md.WriteString("\n\n---\n")
+
+	if email.InReplyTo != "" || email.References != "" {
+		md.WriteString("\n## Thread Information\n\n")
+		if email.MessageID != "" {
+			md.WriteString(fmt.Sprintf("- **Message ID:** `%s`\n", email.MessageID))
+		}
+		if email.InReplyTo != "" {
+			md.WriteString(fmt.Sprintf("- **In Reply To:** `%s`\n", email.InReplyTo))
+		}
+		if email.References != "" {
+			md.WriteString(fmt.Sprintf("- **References:** `%s`\n", email.References))
+		}
+	}
+
+	return md.String()
+}
+
+// renderBody picks the text to put under "## Message". Plain text is
+// preferred verbatim; an HTML-only body is rewritten so cid: image
+// references point at the attachments saved to disk, then converted to
+// text.
+func renderBody(email *emlx.Email, cidPaths map[string]string) string {
+	if email.TextBody != "" {
+		return email.TextBody
+	}
+	if email.HTMLBody == "" {
+		return "[No readable content found]"
+	}
+	return htmlToText(rewriteCIDLinks(email.HTMLBody, cidPaths))
+}
+
+func rewriteCIDLinks(htmlBody string, cidPaths map[string]string) string {
+	for cid, path := range cidPaths {
+		htmlBody = strings.ReplaceAll(htmlBody, "cid:"+cid, path)
+	}
+	return htmlBody
+}
+
+func printExtractionSummary(result *ExtractionResult) {
+	fmt.Fprintf(os.Stderr, "\n📧 Email: %s\n", result.Email.Subject)
+	fmt.Fprintf(os.Stderr, "📁 Output directory: %s\n", result.OutputDir)
+	fmt.Fprintf(os.Stderr, "📝 Output file(s): %s\n", strings.Join(result.OutputFiles, ", "))
+	fmt.Fprintf(os.Stderr, "📎 Attachments extracted: %d\n", len(result.Attachments))
+	if len(result.Attachments) > 0 {
+		fmt.Fprintf(os.Stderr, "   Attachment files:\n")
+		for _, att := range result.Attachments {
+			size := formatFileSize(att.Size)
+			fmt.Fprintf(os.Stderr, "   - %s (%s)\n", att.Filename, size)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", strings.Repeat("=", 80))
+	fmt.Fprintf(os.Stderr, "EXTRACTED CONTENT:\n")
+	fmt.Fprintf(os.Stderr, "%s\n", strings.Repeat("=", 80))
+	fmt.Println(result.Markdown)
+	fmt.Fprintf(os.Stderr, "%s\n", strings.Repeat("=", 80))
+}
+
+func cleanupExtraction(outputDir string) {
+	if err := os.RemoveAll(outputDir); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠️  Warning: Failed to clean up: %v\n", err)
+	} else {
+		fmt.Fprintf(os.Stderr, "\n🧹 Cleaned up: %s\n", outputDir)
+	}
+}
+
+// Helper functions
+
+func sanitizeFilename(name string) string {
+	re := regexp.MustCompile(`[^\w\s\-.]`)
+	name = re.ReplaceAllString(name, "_")
+	reSpaces := regexp.MustCompile(`\s+`)
+	name = reSpaces.ReplaceAllString(name, "_")
+	if name == "" {
+		name = "attachment"
+	}
+	return name
+}
+
+func makeUniqueFilepath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	dir := filepath.Dir(path)
+	filename := filepath.Base(path)
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+
+	counter := 1
+	for {
+		newFilename := fmt.Sprintf("%s_%d%s", base, counter, ext)
+		newPath := filepath.Join(dir, newFilename)
+		if _, err := os.Stat(newPath); os.IsNotExist(err) {
+			return newPath
+		}
+		counter++
+	}
+}
+
+func formatFileSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func expandPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return absPath
+}
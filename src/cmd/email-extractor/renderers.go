@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// jsonInlineThreshold is the largest attachment size that gets base64-inlined
+// into the JSON output; anything bigger is referenced by its on-disk path
+// instead, so a batch of large attachments doesn't blow up index.json-sized
+// consumers.
+const jsonInlineThreshold = 64 * 1024
+
+// Renderer turns one ExtractionResult into a specific output format.
+// Implementations must not mutate result; Render only needs read access to
+// the parsed email, the attachments already saved under result.OutputDir,
+// and result.CidPaths (Content-ID -> attachment path) to resolve inline
+// images.
+type Renderer interface {
+	Render(w io.Writer, r *ExtractionResult) error
+	// Extension is the file extension, including the leading dot, this
+	// renderer's output is saved under (e.g. ".md").
+	Extension() string
+}
+
+// rendererFor returns the Renderer for a --format value, or nil if format
+// isn't one of "md" (or its alias "markdown"), "json", "html" or "org".
+func rendererFor(format string) Renderer {
+	switch format {
+	case "md", "markdown":
+		return markdownRenderer{}
+	case "json":
+		return jsonRenderer{}
+	case "html":
+		return htmlFileRenderer{}
+	case "org":
+		return orgRenderer{}
+	default:
+		return nil
+	}
+}
+
+// markdownRenderer is the tool's original output format.
+type markdownRenderer struct{}
+
+func (markdownRenderer) Extension() string { return ".md" }
+
+func (markdownRenderer) Render(w io.Writer, r *ExtractionResult) error {
+	_, err := io.WriteString(w, createEmailMarkdown(r.Email, r.Attachments, r.CidPaths))
+	return err
+}
+
+// jsonRenderer produces a machine-readable record of the email, suitable as
+// a preprocessing step for LLM pipelines or search indexers. Attachments up
+// to jsonInlineThreshold are inlined as base64; larger ones are left on disk
+// and referenced by path.
+type jsonRenderer struct{}
+
+func (jsonRenderer) Extension() string { return ".json" }
+
+type jsonEmail struct {
+	Subject     string           `json:"subject"`
+	From        string           `json:"from"`
+	To          []string         `json:"to,omitempty"`
+	Cc          []string         `json:"cc,omitempty"`
+	Date        string           `json:"date"`
+	MessageID   string           `json:"message_id,omitempty"`
+	InReplyTo   string           `json:"in_reply_to,omitempty"`
+	References  string           `json:"references,omitempty"`
+	Protocol    string           `json:"protocol,omitempty"`
+	TextBody    string           `json:"text_body,omitempty"`
+	HTMLBody    string           `json:"html_body,omitempty"`
+	Attachments []jsonAttachment `json:"attachments,omitempty"`
+}
+
+type jsonAttachment struct {
+	Filename   string `json:"filename"`
+	Size       int64  `json:"size"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Path       string `json:"path,omitempty"`
+	DataBase64 string `json:"data_base64,omitempty"`
+}
+
+func (jsonRenderer) Render(w io.Writer, r *ExtractionResult) error {
+	email := r.Email
+	out := jsonEmail{
+		Subject:    email.Subject,
+		From:       email.From,
+		To:         email.To,
+		Cc:         email.Cc,
+		Date:       email.Date,
+		MessageID:  email.MessageID,
+		InReplyTo:  email.InReplyTo,
+		References: email.References,
+		Protocol:   email.Protocol,
+		TextBody:   email.TextBody,
+		HTMLBody:   email.HTMLBody,
+	}
+
+	for _, att := range r.Attachments {
+		ja := jsonAttachment{Filename: att.Filename, Size: att.Size, Truncated: att.Truncated}
+		if data, err := inlineableAttachment(r.OutputDir, att); err == nil {
+			ja.DataBase64 = base64.StdEncoding.EncodeToString(data)
+		} else {
+			ja.Path = att.Path
+		}
+		out.Attachments = append(out.Attachments, ja)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(out)
+}
+
+// inlineableAttachment reads att's content from disk if it's small enough
+// to inline, returning an error (without reading) otherwise. A Rendered
+// attachment's Path points at a nested message's own rendered output file,
+// not raw attachment bytes, so it is never inlined regardless of size.
+func inlineableAttachment(outputDir string, att Attachment) ([]byte, error) {
+	if att.Rendered {
+		return nil, fmt.Errorf("%s is a nested message, not inlineable content", att.Filename)
+	}
+	if att.Size > jsonInlineThreshold {
+		return nil, fmt.Errorf("%s is too large to inline", att.Filename)
+	}
+	return os.ReadFile(filepath.Join(outputDir, att.Path))
+}
+
+// htmlFileRenderer produces a single self-contained HTML file: the body plus
+// every attachment and embedded image, all embedded as data: URIs so the
+// file can be shared or archived without its attachments/ directory.
+type htmlFileRenderer struct{}
+
+func (htmlFileRenderer) Extension() string { return ".html" }
+
+func (htmlFileRenderer) Render(w io.Writer, r *ExtractionResult) error {
+	email := r.Email
+
+	var body string
+	switch {
+	case email.HTMLBody != "":
+		body = rewriteCIDDataURIs(email.HTMLBody, r)
+	case email.TextBody != "":
+		body = "<pre>" + html.EscapeString(email.TextBody) + "</pre>"
+	default:
+		body = "<p><em>No readable content found</em></p>"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n</head><body>\n", html.EscapeString(email.Subject))
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", html.EscapeString(email.Subject))
+	fmt.Fprintf(&sb, "<p><strong>From:</strong> %s<br>\n", html.EscapeString(email.From))
+	if len(email.To) > 0 {
+		fmt.Fprintf(&sb, "<strong>To:</strong> %s<br>\n", html.EscapeString(strings.Join(email.To, ", ")))
+	}
+	if len(email.Cc) > 0 {
+		fmt.Fprintf(&sb, "<strong>Cc:</strong> %s<br>\n", html.EscapeString(strings.Join(email.Cc, ", ")))
+	}
+	fmt.Fprintf(&sb, "<strong>Date:</strong> %s</p>\n<hr>\n", html.EscapeString(email.Date))
+
+	sb.WriteString(body)
+	sb.WriteString("\n<hr>\n")
+
+	if len(r.Attachments) > 0 {
+		sb.WriteString("<h2>Attachments</h2>\n<ul>\n")
+		for _, att := range r.Attachments {
+			uri, err := dataURI(r.OutputDir, att.Path, att.Filename)
+			if err != nil {
+				fmt.Fprintf(&sb, "<li>%s (unavailable)</li>\n", html.EscapeString(att.Filename))
+				continue
+			}
+			fmt.Fprintf(&sb, "<li><a download=\"%s\" href=\"%s\">%s</a></li>\n",
+				html.EscapeString(att.Filename), uri, html.EscapeString(att.Filename))
+		}
+		sb.WriteString("</ul>\n")
+	}
+
+	sb.WriteString("</body></html>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// rewriteCIDDataURIs replaces cid: references in an HTML body with data:
+// URIs, so the rendered file has no dependency on the attachments/
+// directory.
+func rewriteCIDDataURIs(htmlBody string, r *ExtractionResult) string {
+	for cid, path := range r.CidPaths {
+		uri, err := dataURI(r.OutputDir, path, filepath.Base(path))
+		if err != nil {
+			continue
+		}
+		htmlBody = strings.ReplaceAll(htmlBody, "cid:"+cid, uri)
+	}
+	return htmlBody
+}
+
+// dataURI reads the file at filepath.Join(outputDir, relPath) and encodes it
+// as a data: URI, guessing the MIME type from filename's extension.
+func dataURI(outputDir, relPath, filename string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, relPath))
+	if err != nil {
+		return "", err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// orgRenderer produces an Emacs org-mode document. The message body reuses
+// the same markdown rendering as markdownRenderer and is lightly rewritten
+// to org syntax (links, fenced code blocks); bullet lists and GFM tables are
+// left as-is since org accepts the same syntax for both.
+type orgRenderer struct{}
+
+func (orgRenderer) Extension() string { return ".org" }
+
+func (orgRenderer) Render(w io.Writer, r *ExtractionResult) error {
+	email := r.Email
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#+TITLE: %s\n", email.Subject)
+	fmt.Fprintf(&sb, "#+AUTHOR: %s\n", email.From)
+	fmt.Fprintf(&sb, "#+DATE: %s\n\n", email.Date)
+
+	sb.WriteString("* Metadata\n\n")
+	fmt.Fprintf(&sb, "- From :: %s\n", email.From)
+	if len(email.To) > 0 {
+		fmt.Fprintf(&sb, "- To :: %s\n", strings.Join(email.To, ", "))
+	}
+	if len(email.Cc) > 0 {
+		fmt.Fprintf(&sb, "- Cc :: %s\n", strings.Join(email.Cc, ", "))
+	}
+	fmt.Fprintf(&sb, "- Date :: %s\n", email.Date)
+	if email.Protocol != "" {
+		fmt.Fprintf(&sb, "- Protocol :: %s\n", email.Protocol)
+	}
+	sb.WriteString("\n")
+
+	if len(r.Attachments) > 0 {
+		sb.WriteString("* Attachments\n\n")
+		for _, att := range r.Attachments {
+			size := formatFileSize(att.Size)
+			note := ""
+			if att.Truncated {
+				note = ", truncated"
+			}
+			fmt.Fprintf(&sb, "- [[file:%s][%s]] (%s%s)\n", att.Path, att.Filename, size, note)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("* Message\n\n")
+	sb.WriteString(toOrgMarkup(renderBody(email, r.CidPaths)))
+	sb.WriteString("\n")
+
+	if email.InReplyTo != "" || email.References != "" {
+		sb.WriteString("\n* Thread Information\n\n")
+		if email.MessageID != "" {
+			fmt.Fprintf(&sb, "- Message ID :: %s\n", email.MessageID)
+		}
+		if email.InReplyTo != "" {
+			fmt.Fprintf(&sb, "- In Reply To :: %s\n", email.InReplyTo)
+		}
+		if email.References != "" {
+			fmt.Fprintf(&sb, "- References :: %s\n", email.References)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var orgLinkRe = regexp.MustCompile(`\[([^\]]*)\]\(([^)]+)\)`)
+
+// toOrgMarkup rewrites the handful of markdown constructs renderBody emits
+// into their org-mode equivalents: [text](url) links and fenced code
+// blocks. Lists and GFM tables pass through unchanged, since org's own
+// syntax for both is the same.
+func toOrgMarkup(markdown string) string {
+	text := orgLinkRe.ReplaceAllString(markdown, "[[$2][$1]]")
+
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "```" {
+			continue
+		}
+		if inCode {
+			lines[i] = "#+END_SRC"
+		} else {
+			lines[i] = "#+BEGIN_SRC"
+		}
+		inCode = !inCode
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,461 @@
+package emlx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// parseBody walks the message body in a single pass, filling in email's
+// TextBody, HTMLBody, Attachments, EmbeddedFiles and NestedEmails.
+// mail.Message.Body can only be consumed once, so unlike the original CLI
+// (which read the body once for text and again for attachments), these are
+// all collected together as the MIME tree is walked.
+func parseBody(msg *mail.Message, email *Email, opts Options) error {
+	contentType := msg.Header.Get("Content-Type")
+	if contentType == "" {
+		body, err := io.ReadAll(msg.Body)
+		if err != nil {
+			return err
+		}
+		email.TextBody = strings.TrimSpace(string(body))
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("failed to parse content type: %w", err)
+	}
+
+	// A single-part message (no multipart, no nested message) is the
+	// whole body, regardless of media type.
+	if !strings.HasPrefix(mediaType, "multipart/") && mediaType != "message/rfc822" {
+		body, err := extractPartBody(msg.Body, params)
+		if err != nil {
+			return err
+		}
+		if mediaType == "text/html" {
+			email.HTMLBody = body
+		} else {
+			email.TextBody = body
+		}
+		return nil
+	}
+
+	return walkPart(msg.Body, textproto.MIMEHeader(msg.Header), email, opts)
+}
+
+// walkPart dispatches on the media type of a part (or, at the top level,
+// the whole message), following RFC 5322 rather than the ad-hoc
+// HasPrefix(mediaType, "multipart/") check the CLI used to do.
+func walkPart(r io.Reader, header textproto.MIMEHeader, email *Email, opts Options) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	switch {
+	case mediaType == "multipart/signed" || mediaType == "multipart/encrypted":
+		return walkSignedOrEncrypted(r, params, email, opts)
+
+	case mediaType == "multipart/related":
+		return walkRelated(r, params, email, opts)
+
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return walkMultipart(r, params, email, opts)
+
+	case mediaType == "message/rfc822":
+		return parseNestedMessage(r, header, email, opts)
+	}
+
+	return classifyLeafPart(r, header, mediaType, params, email, opts)
+}
+
+// walkMultipart handles the generic multipart subtypes (mixed,
+// alternative, digest, ...): every part is classified independently and
+// contributes to the same Email.
+func walkMultipart(r io.Reader, params map[string]string, email *Email, opts Options) error {
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil
+	}
+
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := walkPart(part, part.Header, email, opts); err != nil {
+			return err
+		}
+	}
+}
+
+// walkRelated handles multipart/related: the first part is the root
+// (usually the HTML body, possibly itself a multipart/alternative), and
+// every other part is a resource the root references by Content-ID, e.g.
+// inline images, rather than something to drop for lacking an explicit
+// Content-Disposition: inline.
+func walkRelated(r io.Reader, params map[string]string, email *Email, opts Options) error {
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil
+	}
+
+	mr := multipart.NewReader(r, boundary)
+	index := 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if index == 0 {
+			if err := walkPart(part, part.Header, email, opts); err != nil {
+				return err
+			}
+			index++
+			continue
+		}
+
+		if err := addEmbeddedFile(part, email, opts); err != nil {
+			return err
+		}
+		index++
+	}
+}
+
+// addEmbeddedFile stores a multipart/related resource part as an
+// EmbeddedFile keyed by its Content-ID. A resource without a Content-ID
+// can't be referenced by the root part, so it's classified normally
+// instead (it is most likely a plain attachment).
+func addEmbeddedFile(part *multipart.Part, email *Email, opts Options) error {
+	contentID := strings.Trim(part.Header.Get("Content-ID"), "<>")
+	if contentID == "" {
+		return walkPart(part, part.Header, email, opts)
+	}
+
+	partMediaType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if err != nil {
+		partMediaType = "application/octet-stream"
+	}
+
+	data, err := readAll(part, part.Header)
+	if err != nil {
+		return err
+	}
+
+	email.EmbeddedFiles = append(email.EmbeddedFiles, EmbeddedFile{
+		Filename:    decodeHeader(fileNameFromHeader(part.Header)),
+		ContentType: partMediaType,
+		ContentID:   contentID,
+		Data:        data,
+	})
+	return nil
+}
+
+// walkSignedOrEncrypted handles multipart/signed and multipart/encrypted:
+// the first part is walked as the payload, and every other part is kept as
+// a sidecar attachment rather than folded into the body. For
+// multipart/signed that sidecar is the signature; for multipart/encrypted
+// the first part is the control information (e.g. "Version: 1") and the
+// sidecar is the encrypted data that follows it. The "protocol" parameter
+// is recorded on the Email so callers can tell a message was signed or
+// encrypted without attachment-sniffing.
+func walkSignedOrEncrypted(r io.Reader, params map[string]string, email *Email, opts Options) error {
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil
+	}
+	if protocol := params["protocol"]; protocol != "" {
+		email.Protocol = protocol
+	}
+
+	mr := multipart.NewReader(r, boundary)
+	index := 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if index == 0 {
+			if err := walkPart(part, part.Header, email, opts); err != nil {
+				return err
+			}
+			index++
+			continue
+		}
+
+		partMediaType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			partMediaType = "application/octet-stream"
+		}
+		filename := decodeHeader(fileNameFromHeader(part.Header))
+		if filename == "" {
+			filename = signatureFilename(partMediaType)
+		}
+
+		attachment, err := captureAttachment(part, part.Header, Attachment{
+			Filename:    filename,
+			ContentType: partMediaType,
+		}, opts)
+		if err != nil {
+			if !skippableAttachmentError(err) {
+				return err
+			}
+			email.Warnings = append(email.Warnings, err.Error())
+			index++
+			continue
+		}
+		email.Attachments = append(email.Attachments, attachment)
+		index++
+	}
+}
+
+func signatureFilename(mediaType string) string {
+	switch mediaType {
+	case "application/pgp-signature":
+		return "signature.asc"
+	case "application/pkcs7-signature", "application/x-pkcs7-signature":
+		return "smime.p7s"
+	case "application/pkcs7-mime", "application/x-pkcs7-mime":
+		return "smime.p7m"
+	default:
+		return "signature.bin"
+	}
+}
+
+// parseNestedMessage parses an attached message/rfc822 part as a full
+// Email of its own and records it under NestedEmails. Its attachments are
+// always buffered in memory (Options.AttachmentSink is not propagated):
+// the sink is tied to a single destination directory, which the caller
+// only knows for the top-level message.
+func parseNestedMessage(r io.Reader, header textproto.MIMEHeader, email *Email, opts Options) error {
+	nested, err := ParseWithOptions(r, Options{MaxAttachmentSize: opts.MaxAttachmentSize})
+	if err != nil {
+		return fmt.Errorf("failed to parse nested message: %w", err)
+	}
+
+	filename := decodeHeader(fileNameFromHeader(header))
+	if filename == "" {
+		filename = "original_message.eml"
+	}
+
+	email.NestedEmails = append(email.NestedEmails, NestedEmail{
+		Filename: filename,
+		Email:    nested,
+	})
+	return nil
+}
+
+// classifyLeafPart handles a non-multipart part: an attachment, an inline
+// embedded file, or a candidate for the text/HTML body.
+func classifyLeafPart(r io.Reader, header textproto.MIMEHeader, mediaType string, params map[string]string, email *Email, opts Options) error {
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	contentID := strings.Trim(header.Get("Content-ID"), "<>")
+	filename := decodeHeader(fileNameFromHeader(header))
+
+	switch {
+	case disposition == "attachment":
+		attachment, err := captureAttachment(r, header, Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   contentID,
+		}, opts)
+		if err != nil {
+			if !skippableAttachmentError(err) {
+				return err
+			}
+			email.Warnings = append(email.Warnings, err.Error())
+			break
+		}
+		email.Attachments = append(email.Attachments, attachment)
+
+	case disposition == "inline" && contentID != "":
+		data, err := readAll(r, header)
+		if err != nil {
+			return err
+		}
+		email.EmbeddedFiles = append(email.EmbeddedFiles, EmbeddedFile{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   contentID,
+			Data:        data,
+		})
+
+	case mediaType == "text/plain" && email.TextBody == "":
+		body, err := extractPartBody(r, params)
+		if err != nil {
+			return err
+		}
+		email.TextBody = body
+
+	case mediaType == "text/html" && email.HTMLBody == "":
+		body, err := extractPartBody(r, params)
+		if err != nil {
+			return err
+		}
+		email.HTMLBody = body
+	}
+
+	return nil
+}
+
+// fileNameFromHeader looks up a part's filename from Content-Disposition
+// first, falling back to the Content-Type "name" parameter.
+func fileNameFromHeader(header textproto.MIMEHeader) string {
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		if fn := params["filename"]; fn != "" {
+			return fn
+		}
+	}
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+		if fn := params["name"]; fn != "" {
+			return fn
+		}
+	}
+	return ""
+}
+
+// extractPartBody reads a text part, decoding its declared charset to
+// UTF-8 if needed.
+func extractPartBody(r io.Reader, params map[string]string) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	if enc := params["charset"]; enc != "" && !strings.EqualFold(enc, "utf-8") {
+		if decoded, err := decodeContent(content, enc); err == nil {
+			content = decoded
+		}
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// decodeReader wraps r with a reader that undoes the Content-Transfer-
+// Encoding declared in header. Decoding this way, rather than reading the
+// whole part into a buffer and decoding that, is what lets attachment
+// content be streamed straight to its destination without doubling memory.
+func decodeReader(r io.Reader, header textproto.MIMEHeader) io.Reader {
+	switch header.Get("Content-Transfer-Encoding") {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// readAll fully decodes a part into memory. Used for embedded files, which
+// are small enough that streaming them to a caller-provided destination
+// isn't worth the API surface.
+func readAll(r io.Reader, header textproto.MIMEHeader) (io.Reader, error) {
+	content, err := io.ReadAll(decodeReader(r, header))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode part: %w", err)
+	}
+	return bytes.NewReader(content), nil
+}
+
+// sinkOpenError reports that opts.AttachmentSink failed to open a
+// destination for an attachment, before anything was written. Unlike a
+// failure partway through copying the decoded content, nothing has to be
+// rolled back, so callers can skip just this one attachment and keep
+// parsing the rest of the email.
+type sinkOpenError struct {
+	filename string
+	err      error
+}
+
+func (e *sinkOpenError) Error() string {
+	return fmt.Sprintf("failed to open destination for attachment %q: %v", e.filename, e.err)
+}
+
+func (e *sinkOpenError) Unwrap() error { return e.err }
+
+// skippableAttachmentError reports whether err came from captureAttachment
+// failing in a way that only affects the one attachment, so the caller can
+// log it as a warning and move on instead of aborting the whole parse.
+func skippableAttachmentError(err error) bool {
+	var sinkErr *sinkOpenError
+	return errors.As(err, &sinkErr)
+}
+
+// captureAttachment decodes part's content and either buffers it into
+// Attachment.Data or, if opts.AttachmentSink is set, streams it straight
+// into the caller-provided destination via io.Copy. Either way,
+// opts.MaxAttachmentSize caps how much is kept.
+func captureAttachment(r io.Reader, header textproto.MIMEHeader, meta Attachment, opts Options) (Attachment, error) {
+	src := decodeReader(r, header)
+
+	if opts.AttachmentSink != nil {
+		dst, err := opts.AttachmentSink(meta)
+		if err != nil {
+			return meta, &sinkOpenError{filename: meta.Filename, err: err}
+		}
+		size, truncated, copyErr := copyLimited(dst, src, opts.MaxAttachmentSize)
+		if closeErr := dst.Close(); copyErr == nil {
+			copyErr = closeErr
+		}
+		if copyErr != nil {
+			return meta, fmt.Errorf("failed to stream attachment %q: %w", meta.Filename, copyErr)
+		}
+		meta.Size = size
+		meta.Truncated = truncated
+		return meta, nil
+	}
+
+	var buf bytes.Buffer
+	size, truncated, err := copyLimited(&buf, src, opts.MaxAttachmentSize)
+	if err != nil {
+		return meta, fmt.Errorf("failed to decode attachment %q: %w", meta.Filename, err)
+	}
+	meta.Data = bytes.NewReader(buf.Bytes())
+	meta.Size = size
+	meta.Truncated = truncated
+	return meta, nil
+}
+
+// copyLimited copies src into dst, capping at max bytes (max <= 0 means
+// unlimited). If src has more data than the cap, the remainder is drained
+// into io.Discard so the underlying multipart reader stays positioned
+// correctly for the next part, and truncated is reported true.
+func copyLimited(dst io.Writer, src io.Reader, max int64) (size int64, truncated bool, err error) {
+	if max <= 0 {
+		size, err = io.Copy(dst, src)
+		return size, false, err
+	}
+
+	size, err = io.Copy(dst, io.LimitReader(src, max))
+	if err != nil || size < max {
+		return size, false, err
+	}
+
+	extra, err := io.Copy(io.Discard, src)
+	if err != nil {
+		return size, false, err
+	}
+	return size, extra > 0, nil
+}
@@ -0,0 +1,263 @@
+package emlx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseMultipartRelated(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Subject: related\r\n" +
+		"Content-Type: multipart/related; boundary=\"R\"\r\n" +
+		"\r\n" +
+		"--R\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<html><img src=\"cid:img1\"></html>\r\n" +
+		"--R\r\n" +
+		"Content-Type: image/png\r\n" +
+		"Content-ID: <img1>\r\n" +
+		"\r\n" +
+		"pngbytes\r\n" +
+		"--R--\r\n"
+
+	email, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !strings.Contains(email.HTMLBody, "cid:img1") {
+		t.Errorf("HTMLBody = %q, want it to still reference cid:img1", email.HTMLBody)
+	}
+	if len(email.EmbeddedFiles) != 1 {
+		t.Fatalf("got %d embedded files, want 1", len(email.EmbeddedFiles))
+	}
+	ef := email.EmbeddedFiles[0]
+	if ef.ContentID != "img1" || ef.ContentType != "image/png" {
+		t.Errorf("embedded file = %+v, want ContentID=img1 ContentType=image/png", ef)
+	}
+	if len(email.Attachments) != 0 {
+		t.Errorf("got %d attachments, want 0 (the image has a Content-ID and belongs in EmbeddedFiles)", len(email.Attachments))
+	}
+}
+
+func TestParseMultipartRelatedResourceWithoutContentID(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"Subject: related\r\n" +
+		"Content-Type: multipart/related; boundary=\"R\"\r\n" +
+		"\r\n" +
+		"--R\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<html>hi</html>\r\n" +
+		"--R\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"data.bin\"\r\n" +
+		"\r\n" +
+		"filedata\r\n" +
+		"--R--\r\n"
+
+	email, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(email.EmbeddedFiles) != 0 {
+		t.Errorf("got %d embedded files, want 0", len(email.EmbeddedFiles))
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "data.bin" {
+		t.Errorf("attachments = %+v, want a single data.bin attachment", email.Attachments)
+	}
+}
+
+func TestParseMultipartSigned(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"Subject: signed\r\n" +
+		"Content-Type: multipart/signed; protocol=\"application/pgp-signature\"; boundary=\"S\"\r\n" +
+		"\r\n" +
+		"--S\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"signed body\r\n" +
+		"--S\r\n" +
+		"Content-Type: application/pgp-signature\r\n" +
+		"\r\n" +
+		"-----BEGIN PGP SIGNATURE-----\r\nabc\r\n-----END PGP SIGNATURE-----\r\n" +
+		"--S--\r\n"
+
+	email, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if email.Protocol != "application/pgp-signature" {
+		t.Errorf("Protocol = %q, want application/pgp-signature", email.Protocol)
+	}
+	if email.TextBody != "signed body" {
+		t.Errorf("TextBody = %q, want %q", email.TextBody, "signed body")
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "signature.asc" {
+		t.Errorf("attachments = %+v, want a single signature.asc attachment", email.Attachments)
+	}
+}
+
+func TestParseMultipartEncrypted(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"Subject: encrypted\r\n" +
+		"Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=\"E\"\r\n" +
+		"\r\n" +
+		"--E\r\n" +
+		"Content-Type: application/pgp-encrypted\r\n" +
+		"\r\n" +
+		"Version: 1\r\n" +
+		"--E\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"\r\n" +
+		"encrypted blob\r\n" +
+		"--E--\r\n"
+
+	email, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if email.Protocol != "application/pgp-encrypted" {
+		t.Errorf("Protocol = %q, want application/pgp-encrypted", email.Protocol)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "signature.bin" {
+		t.Errorf("attachments = %+v, want a single signature.bin attachment (no recognized signature media type)", email.Attachments)
+	}
+}
+
+func TestParseNestedMessage(t *testing.T) {
+	nested := "From: inner@example.com\r\n" +
+		"Subject: inner subject\r\n" +
+		"\r\n" +
+		"inner body\r\n"
+
+	raw := "From: a@example.com\r\n" +
+		"Subject: outer\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"M\"\r\n" +
+		"\r\n" +
+		"--M\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"outer body\r\n" +
+		"--M\r\n" +
+		"Content-Type: message/rfc822\r\n" +
+		"Content-Disposition: attachment; filename=\"original.eml\"\r\n" +
+		"\r\n" +
+		nested +
+		"--M--\r\n"
+
+	email, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if email.TextBody != "outer body" {
+		t.Errorf("TextBody = %q, want %q", email.TextBody, "outer body")
+	}
+	if len(email.NestedEmails) != 1 {
+		t.Fatalf("got %d nested emails, want 1", len(email.NestedEmails))
+	}
+	ne := email.NestedEmails[0]
+	if ne.Filename != "original.eml" {
+		t.Errorf("nested Filename = %q, want original.eml", ne.Filename)
+	}
+	if ne.Email.Subject != "inner subject" || ne.Email.TextBody != "inner body" {
+		t.Errorf("nested email = %+v, want Subject=%q TextBody=%q", ne.Email, "inner subject", "inner body")
+	}
+}
+
+func TestMaxAttachmentSizeTruncatesAndKeepsParsing(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"Subject: big attachment\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"M\"\r\n" +
+		"\r\n" +
+		"--M\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"body text\r\n" +
+		"--M\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"big.bin\"\r\n" +
+		"\r\n" +
+		"0123456789\r\n" +
+		"--M--\r\n"
+
+	email, err := ParseWithOptions(strings.NewReader(raw), Options{MaxAttachmentSize: 4})
+	if err != nil {
+		t.Fatalf("ParseWithOptions returned error: %v", err)
+	}
+	if email.TextBody != "body text" {
+		t.Errorf("TextBody = %q, want %q", email.TextBody, "body text")
+	}
+	if len(email.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(email.Attachments))
+	}
+	att := email.Attachments[0]
+	if !att.Truncated {
+		t.Errorf("attachment Truncated = false, want true")
+	}
+	if att.Size != 4 {
+		t.Errorf("attachment Size = %d, want 4", att.Size)
+	}
+	data, err := io.ReadAll(att.Data)
+	if err != nil {
+		t.Fatalf("reading att.Data: %v", err)
+	}
+	if string(data) != "0123" {
+		t.Errorf("att.Data = %q, want %q", data, "0123")
+	}
+}
+
+func TestCaptureAttachmentSinkOpenFailureIsSkippedNotFatal(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"Subject: two attachments\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"M\"\r\n" +
+		"\r\n" +
+		"--M\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"body text\r\n" +
+		"--M\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"bad.bin\"\r\n" +
+		"\r\n" +
+		"bad\r\n" +
+		"--M\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"good.bin\"\r\n" +
+		"\r\n" +
+		"good\r\n" +
+		"--M--\r\n"
+
+	opts := Options{
+		AttachmentSink: func(meta Attachment) (io.WriteCloser, error) {
+			if meta.Filename == "bad.bin" {
+				return nil, fmt.Errorf("simulated disk full")
+			}
+			return nopWriteCloser{&bytes.Buffer{}}, nil
+		},
+	}
+
+	email, err := ParseWithOptions(strings.NewReader(raw), opts)
+	if err != nil {
+		t.Fatalf("ParseWithOptions returned error: %v, want the failing attachment to be skipped instead", err)
+	}
+	if email.TextBody != "body text" {
+		t.Errorf("TextBody = %q, want %q", email.TextBody, "body text")
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "good.bin" {
+		t.Errorf("attachments = %+v, want only good.bin", email.Attachments)
+	}
+	if len(email.Warnings) != 1 {
+		t.Errorf("got %d warnings, want 1 recording the bad.bin failure", len(email.Warnings))
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
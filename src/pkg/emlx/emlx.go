@@ -0,0 +1,147 @@
+// Package emlx parses RFC 5322 / MIME email messages (.eml) into a
+// structured, in-memory representation. It does no file I/O and has no CLI
+// dependencies, so it can be embedded in other Go programs (bots, indexers,
+// test harnesses) without shelling out to the email-extractor binary. The
+// API is deliberately close to Dusan Kasan's parsemail package.
+package emlx
+
+import (
+	"fmt"
+	"io"
+	"net/mail"
+)
+
+// Attachment is a MIME part the sender intended the recipient to download,
+// i.e. one with Content-Disposition: attachment, or a non-payload part of a
+// multipart/signed or multipart/encrypted message kept as a sidecar.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+
+	// Size is the number of decoded bytes kept, after MaxAttachmentSize is
+	// applied. Truncated is set if the part had more data than that.
+	Size      int64
+	Truncated bool
+
+	// Data holds the decoded content in memory. It is nil when
+	// Options.AttachmentSink was used to stream the content elsewhere instead.
+	Data io.Reader
+}
+
+// EmbeddedFile is an inline MIME part referenced from the HTML body via a
+// cid: URI (Content-Disposition: inline plus a Content-ID, or any non-root
+// part of a multipart/related message that has a Content-ID), as opposed to
+// a file the sender meant the recipient to save.
+type EmbeddedFile struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Data        io.Reader
+}
+
+// NestedEmail is an attached message/rfc822 part: a full email embedded
+// inside another one (e.g. a forwarded message kept as its own attachment).
+type NestedEmail struct {
+	Filename string
+	Email    *Email
+}
+
+// Email is the structured result of parsing a single .eml message.
+type Email struct {
+	Header mail.Header
+
+	Subject string
+	From    string
+	To      []string
+	Cc      []string
+	Date    string
+
+	// Thread fields, useful for grouping related messages.
+	MessageID  string
+	InReplyTo  string
+	References string
+
+	// Protocol is the "protocol" Content-Type parameter of a
+	// multipart/signed or multipart/encrypted message, e.g.
+	// "application/pgp-signature". Empty for plain messages.
+	Protocol string
+
+	TextBody string
+	HTMLBody string
+
+	Attachments   []Attachment
+	EmbeddedFiles []EmbeddedFile
+	NestedEmails  []NestedEmail
+
+	// Warnings accumulates non-fatal problems encountered while parsing the
+	// body, e.g. an attachment whose AttachmentSink destination couldn't be
+	// opened. The rest of the email (other attachments, the text/HTML body)
+	// is still parsed; callers that want to surface these to a user should
+	// log them rather than treat the parse as failed.
+	Warnings []string
+}
+
+// AttachmentSink creates the destination for one attachment's decoded
+// content, keyed by its metadata (Filename and ContentType are populated;
+// Size and Truncated are not yet known). When set via Options, attachment
+// content is streamed directly into the returned writer instead of being
+// buffered into Attachment.Data, which avoids doubling memory for large
+// attachments. The caller is responsible for naming/deduplicating the
+// destination; Parse closes the returned writer once the attachment's
+// content has been copied into it.
+type AttachmentSink func(meta Attachment) (io.WriteCloser, error)
+
+// Options configures how Parse handles attachments.
+type Options struct {
+	// MaxAttachmentSize caps how many decoded bytes of each attachment are
+	// kept; zero means unlimited. Bytes beyond the cap are discarded (but
+	// still read, so the MIME reader stays positioned correctly for the
+	// next part), and the resulting Attachment has Truncated set.
+	MaxAttachmentSize int64
+
+	// AttachmentSink, if set, streams each attachment's decoded content
+	// into a caller-provided writer (e.g. an *os.File) instead of
+	// buffering it in Attachment.Data.
+	AttachmentSink AttachmentSink
+}
+
+// Parse reads a single RFC 5322 message from r and returns its structured
+// representation. It consumes r fully; the body can only be read once, so
+// the caller should not attempt to re-read r afterwards.
+func Parse(r io.Reader) (*Email, error) {
+	return ParseWithOptions(r, Options{})
+}
+
+// ParseWithOptions is like Parse but lets the caller control how
+// attachments are handled; see Options.
+func ParseWithOptions(r io.Reader, opts Options) (*Email, error) {
+	email, msg, err := ParseHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := ParseBody(msg, email, opts); err != nil {
+		return nil, fmt.Errorf("failed to extract email body: %w", err)
+	}
+	return email, nil
+}
+
+// ParseHeader reads only the RFC 5322 headers from r and returns the
+// header-derived fields of an Email alongside the parsed *mail.Message,
+// whose Body is still unread. This lets a caller inspect, say, Subject
+// before deciding where attachments should be streamed (via Options.
+// AttachmentSink), then finish parsing with ParseBody.
+func ParseHeader(r io.Reader) (*Email, *mail.Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+	return parseMetadata(msg), msg, nil
+}
+
+// ParseBody parses msg's body (as returned by ParseHeader) into email,
+// honoring opts for attachment handling. msg.Body can only be consumed
+// once.
+func ParseBody(msg *mail.Message, email *Email, opts Options) error {
+	return parseBody(msg, email, opts)
+}
@@ -0,0 +1,28 @@
+package emlx
+
+import "net/mail"
+
+// parseMetadata extracts the header-derived fields of an Email. The body
+// fields are filled in separately by parseBody, since they require
+// consuming msg.Body.
+func parseMetadata(msg *mail.Message) *Email {
+	header := msg.Header
+
+	dateStr := header.Get("Date")
+	formattedDate := dateStr
+	if t, err := mail.ParseDate(dateStr); err == nil {
+		formattedDate = t.Format("2006-01-02 15:04:05")
+	}
+
+	return &Email{
+		Header:     header,
+		Subject:    decodeHeader(header.Get("Subject")),
+		From:       formatEmailAddress(header.Get("From")),
+		To:         parseAddressList(header.Get("To")),
+		Cc:         parseAddressList(header.Get("Cc")),
+		Date:       formattedDate,
+		MessageID:  header.Get("Message-ID"),
+		InReplyTo:  header.Get("In-Reply-To"),
+		References: header.Get("References"),
+	}
+}
@@ -0,0 +1,74 @@
+package emlx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+func decodeHeader(s string) string {
+	dec := new(mime.WordDecoder)
+	decoded, err := dec.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+func formatEmailAddress(addr string) string {
+	if addr == "" {
+		return "Unknown"
+	}
+
+	addresses, err := mail.ParseAddressList(addr)
+	if err != nil || len(addresses) == 0 {
+		return addr
+	}
+
+	if addresses[0].Name != "" {
+		return fmt.Sprintf("%s <%s>", addresses[0].Name, addresses[0].Address)
+	}
+	return addresses[0].Address
+}
+
+func parseAddressList(addrs string) []string {
+	if addrs == "" {
+		return nil
+	}
+
+	addresses, err := mail.ParseAddressList(addrs)
+	if err != nil {
+		// Fallback to simple split
+		parts := strings.Split(addrs, ",")
+		var result []string
+		for _, p := range parts {
+			if trimmed := strings.TrimSpace(p); trimmed != "" {
+				result = append(result, trimmed)
+			}
+		}
+		return result
+	}
+
+	var result []string
+	for _, addr := range addresses {
+		if addr.Name != "" {
+			result = append(result, fmt.Sprintf("%s <%s>", addr.Name, addr.Address))
+		} else {
+			result = append(result, addr.Address)
+		}
+	}
+	return result
+}
+
+func decodeContent(content []byte, encoding string) ([]byte, error) {
+	reader, err := charset.NewReaderLabel(encoding, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}